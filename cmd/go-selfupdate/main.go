@@ -2,25 +2,66 @@ package main
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/klauspost/compress/zstd"
+	"github.com/kr/binarydist"
 )
 
-var version, genDir string
+var version, genDir, privateKeyFlag, patchesFromFlag string
 
 type current struct {
-	Version string
+	Version   string
+	Sha256    string `json:",omitempty"`
+	Signature string `json:",omitempty"`
 }
 
-func createUpdate(path string, platform string) {
+// loadPrivateKey reads a hex-encoded Ed25519 seed from path. An empty path
+// means no signing key was configured.
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := hex.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("private key must be hex-encoded: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("private key must be a %d-byte hex-encoded ed25519 seed", ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+func createUpdate(path string, platform string, privateKey ed25519.PrivateKey) {
+	f, err := os.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+
 	c := current{Version: version}
 
+	if privateKey != nil {
+		sum := sha256.Sum256(f)
+		c.Sha256 = hex.EncodeToString(sum[:])
+		c.Signature = hex.EncodeToString(ed25519.Sign(privateKey, sum[:]))
+	}
+
 	b, err := json.MarshalIndent(c, "", "    ")
 	if err != nil {
 		fmt.Println("error:", err)
@@ -34,16 +75,52 @@ func createUpdate(path string, platform string) {
 
 	var buf bytes.Buffer
 	w, _ := zstd.NewWriter(&buf, zstd.EOption(zstd.WithEncoderLevel(4)))
-	f, err := os.ReadFile(path)
-	if err != nil {
-		panic(err)
-	}
 	w.Write(f)
 	w.Close() // You must close this first to flush the bytes to the buffer.
-	//err = os.WriteFile(filepath.Join(genDir, version, platform+".zst"), buf.Bytes(), 0755)
 	os.WriteFile(filepath.Join(genDir, version, platform+".zst"), buf.Bytes(), 0755)
 }
 
+// createPatches reads every prior release binary out of patchesDir (each
+// file named after the version it represents, e.g. "1.2.0") and bsdiffs
+// the new binary at path against each of them, writing the result to
+// genDir/patches/{oldVersion}/{platform}.bsdiff.zst so Updater.Update can
+// fetch a small delta instead of the full binary.
+func createPatches(path, platform, patchesDir string) {
+	newBin, err := os.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+
+	files, err := os.ReadDir(patchesDir)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, file := range files {
+		oldVersion := file.Name()
+		oldBin, err := os.ReadFile(filepath.Join(patchesDir, oldVersion))
+		if err != nil {
+			fmt.Println("error reading", oldVersion, err)
+			continue
+		}
+
+		var diff bytes.Buffer
+		if err := binarydist.Diff(bytes.NewReader(oldBin), bytes.NewReader(newBin), &diff); err != nil {
+			fmt.Println("error diffing", oldVersion, err)
+			continue
+		}
+
+		var buf bytes.Buffer
+		w, _ := zstd.NewWriter(&buf, zstd.EOption(zstd.WithEncoderLevel(4)))
+		w.Write(diff.Bytes())
+		w.Close()
+
+		outDir := filepath.Join(genDir, "patches", oldVersion)
+		os.MkdirAll(outDir, 0755)
+		os.WriteFile(filepath.Join(outDir, platform+".bsdiff.zst"), buf.Bytes(), 0755)
+	}
+}
+
 func printUsage() {
 	fmt.Println("")
 	fmt.Println("Positional arguments:")
@@ -68,6 +145,10 @@ func main() {
 	}
 	platformFlag := flag.String("platform", defaultPlatform,
 		"Target platform in the form OS-ARCH. Defaults to running os/arch or the combination of the environment variables GOOS and GOARCH if both are set.")
+	flag.StringVar(&privateKeyFlag, "private-key", "",
+		"Path to a hex-encoded ed25519 private key seed. When set, the sha256 and signature of each binary are written into its platform.json.")
+	flag.StringVar(&patchesFromFlag, "patches-from", "",
+		"Directory of prior release binaries, named by version, to generate bsdiff patches against.")
 
 	flag.Parse()
 	if flag.NArg() < 2 {
@@ -81,6 +162,11 @@ func main() {
 	version = flag.Arg(1)
 	genDir = *outputDirFlag
 
+	privateKey, err := loadPrivateKey(privateKeyFlag)
+	if err != nil {
+		panic(err)
+	}
+
 	createBuildDir()
 
 	// If dir is given create update for each file
@@ -90,14 +176,23 @@ func main() {
 	}
 
 	if fi.IsDir() {
+		if patchesFromFlag != "" {
+			fmt.Println("error: -patches-from is not supported with a directory of platform binaries; pass a single binary path instead")
+			os.Exit(1)
+		}
+
 		files, err := os.ReadDir(appPath)
 		if err == nil {
 			for _, file := range files {
-				createUpdate(filepath.Join(appPath, file.Name()), file.Name())
+				createUpdate(filepath.Join(appPath, file.Name()), file.Name(), privateKey)
 			}
 			os.Exit(0)
 		}
 	}
 
-	createUpdate(appPath, platform)
+	createUpdate(appPath, platform, privateKey)
+
+	if patchesFromFlag != "" {
+		createPatches(appPath, platform, patchesFromFlag)
+	}
 }
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package selfupdate
+
+// hideFile is a no-op outside Windows: only Windows Explorer hides files
+// based on an attribute bit, and fromStream already falls back to it only
+// when os.Remove fails.
+func hideFile(path string) error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package selfupdate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/url"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/kr/binarydist"
+)
+
+// fetchPatchedBin downloads a bsdiff patch from old to the latest version
+// advertised in u.Info, applies it to old, and returns the resulting
+// binary. The caller is expected to fall back to fetchFullBin if this
+// returns an error, e.g. because no patch exists for u.CurrentVersion or
+// the patched result doesn't match the manifest hash.
+func (u *Updater) fetchPatchedBin(old []byte) ([]byte, error) {
+	patch, err := u.fetchPatch()
+	if err != nil {
+		return nil, err
+	}
+
+	newBin := new(bytes.Buffer)
+	if err := binarydist.Patch(bytes.NewReader(old), newBin, patch); err != nil {
+		return nil, err
+	}
+	bin := newBin.Bytes()
+
+	sum := sha256.Sum256(bin)
+	if u.Info.Sha256 != "" && hex.EncodeToString(sum[:]) != u.Info.Sha256 {
+		return nil, ErrHashMismatch
+	}
+
+	return bin, nil
+}
+
+// fetchPatch downloads and decompresses the bsdiff patch from
+// u.CurrentVersion to u.Info.Version for the running platform.
+func (u *Updater) fetchPatch() (io.Reader, error) {
+	r, err := u.fetch(u.ApiURL + url.QueryEscape(u.CmdName) + "/patches/" + url.QueryEscape(u.CurrentVersion) + "/" + url.QueryEscape(plat) + ".bsdiff.zst")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	patch := new(bytes.Buffer)
+	if _, err := io.Copy(patch, zr); err != nil {
+		return nil, err
+	}
+
+	return patch, nil
+}
@@ -0,0 +1,82 @@
+package selfupdate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/kr/binarydist"
+)
+
+// fakePatchRequester serves a canned response for every Fetch, standing in
+// for the network round trip fetchPatch would otherwise make.
+type fakePatchRequester struct {
+	body []byte
+}
+
+func (f fakePatchRequester) Fetch(url string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(f.body)), nil
+}
+
+// zstdPatch builds a zstd-compressed bsdiff patch from old to newBin, in
+// the shape fetchPatch expects to download.
+func zstdPatch(t *testing.T, old, newBin []byte) []byte {
+	t.Helper()
+
+	var diff bytes.Buffer
+	if err := binarydist.Diff(bytes.NewReader(old), bytes.NewReader(newBin), &diff); err != nil {
+		t.Fatalf("binarydist.Diff: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("zstd.NewWriter: %v", err)
+	}
+	if _, err := w.Write(diff.Bytes()); err != nil {
+		t.Fatalf("zstd write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("zstd close: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestFetchPatchedBin(t *testing.T) {
+	old := []byte("the quick brown fox jumps over the lazy dog, version one")
+	newBin := []byte("the quick brown fox jumps over the lazy dog, version two")
+
+	u := &Updater{
+		CurrentVersion: "1.0.0",
+		Requester:      fakePatchRequester{body: zstdPatch(t, old, newBin)},
+	}
+	sum := sha256.Sum256(newBin)
+	u.Info.Sha256 = hex.EncodeToString(sum[:])
+
+	got, err := u.fetchPatchedBin(old)
+	if err != nil {
+		t.Fatalf("fetchPatchedBin: %v", err)
+	}
+	if !bytes.Equal(got, newBin) {
+		t.Fatalf("fetchPatchedBin = %q, want %q", got, newBin)
+	}
+}
+
+func TestFetchPatchedBinHashMismatch(t *testing.T) {
+	old := []byte("the quick brown fox jumps over the lazy dog, version one")
+	newBin := []byte("the quick brown fox jumps over the lazy dog, version two")
+
+	u := &Updater{
+		CurrentVersion: "1.0.0",
+		Requester:      fakePatchRequester{body: zstdPatch(t, old, newBin)},
+	}
+	u.Info.Sha256 = hex.EncodeToString(sha256.New().Sum(nil))
+
+	if _, err := u.fetchPatchedBin(old); err != ErrHashMismatch {
+		t.Fatalf("fetchPatchedBin = %v, want ErrHashMismatch", err)
+	}
+}
@@ -1,35 +1,177 @@
 package selfupdate
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
-// HTTPRequester is the normal requester that is used and does an HTTP
-// to the URL location requested to retrieve the specified data.
-type HTTPRequester struct{}
+// ErrNotModified is returned by a ConditionalRequester, and treated by
+// Updater as "no update available", when the server answers a conditional
+// request with 304 Not Modified.
+var ErrNotModified = errors.New("selfupdate: manifest not modified")
+
+// Validators holds the cache validators of a previous response, carried
+// forward to make a later request to the same URL conditional.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Requester is the interface Updater uses to fetch manifests and
+// binaries; implement it to plug in a custom transport.
+type Requester interface {
+	// Fetch returns the body of url. An error is returned for a non-200
+	// status code.
+	Fetch(url string) (io.ReadCloser, error)
+}
+
+// ConditionalRequester is implemented by Requesters that can make a
+// cheap "has this changed" request using HTTP validators, so a manifest
+// that hasn't changed isn't re-downloaded on every poll.
+type ConditionalRequester interface {
+	Requester
+	// FetchConditional behaves like Fetch but sends If-None-Match and/or
+	// If-Modified-Since derived from prior, returning the validators from
+	// the new response. If the server answers 304, it returns
+	// ErrNotModified and prior unchanged.
+	FetchConditional(url string, prior Validators) (io.ReadCloser, Validators, error)
+}
+
+// HTTPRequester is the default Requester: a plain HTTP GET with normal
+// certificate verification, optional certificate pinning, and support for
+// conditional requests. The zero value is safe to use.
+type HTTPRequester struct {
+	// Timeout bounds an individual request. Zero means no timeout.
+	Timeout time.Duration
+	// RootCAs overrides the system certificate pool used to verify the
+	// server's certificate. Nil uses the system pool.
+	RootCAs *x509.CertPool
+	// PinnedSHA256, when non-empty, additionally requires the server's
+	// certificate chain to contain a certificate whose SHA-256 hash of
+	// its SubjectPublicKeyInfo matches one of these pins.
+	PinnedSHA256 [][]byte
+	// UserAgent is sent with every request. Defaults to "go-selfupdate".
+	UserAgent string
+	// MaxResponseBytes caps how many bytes of a response body are read.
+	// Zero means no cap.
+	MaxResponseBytes int64
+
+	client *http.Client
+}
+
+// NewHTTPRequester builds an HTTPRequester from opts and pre-builds the
+// *http.Client it will reuse across requests.
+func NewHTTPRequester(opts HTTPRequester) *HTTPRequester {
+	opts.client = opts.newClient()
+	return &opts
+}
+
+func (h *HTTPRequester) newClient() *http.Client {
+	tlsConfig := &tls.Config{RootCAs: h.RootCAs}
+	if len(h.PinnedSHA256) > 0 {
+		tlsConfig.VerifyPeerCertificate = h.verifyPin
+	}
+
+	return &http.Client{
+		Timeout:   h.Timeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+}
+
+func (h *HTTPRequester) verifyPin(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range h.PinnedSHA256 {
+			if subtle.ConstantTimeCompare(sum[:], pin) == 1 {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("go-selfupdate: no certificate in the chain matched a pinned key")
+}
+
+func (h *HTTPRequester) httpClient() *http.Client {
+	if h.client != nil {
+		return h.client
+	}
+	return h.newClient()
+}
+
+func (h *HTTPRequester) userAgent() string {
+	if h.UserAgent != "" {
+		return h.UserAgent
+	}
+	return "go-selfupdate"
+}
 
 // Fetch will return an HTTP request to the specified url and return
 // the body of the result. An error will occur for a non 200 status code.
-func (httpRequester *HTTPRequester) Fetch(url string) (io.ReadCloser, error) {
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
+func (h *HTTPRequester) Fetch(url string) (io.ReadCloser, error) {
+	body, _, err := h.fetch(url, Validators{})
+	return body, err
+}
+
+// FetchConditional implements ConditionalRequester.
+func (h *HTTPRequester) FetchConditional(url string, prior Validators) (io.ReadCloser, Validators, error) {
+	return h.fetch(url, prior)
+}
+
+func (h *HTTPRequester) fetch(url string, prior Validators) (io.ReadCloser, Validators, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+	req.Header.Set("User-Agent", h.userAgent())
+	if prior.ETag != "" {
+		req.Header.Set("If-None-Match", prior.ETag)
+	}
+	if prior.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prior.LastModified)
 	}
 
-	resp, err := client.Get(url)
+	resp, err := h.httpClient().Do(req)
 	if err != nil {
-		return nil, err
+		return nil, Validators{}, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, prior, ErrNotModified
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, Validators{}, fmt.Errorf("bad http status from %s: %v", url, resp.Status)
+	}
+
+	body := io.ReadCloser(resp.Body)
+	if h.MaxResponseBytes > 0 {
+		body = limitedReadCloser{io.LimitReader(resp.Body, h.MaxResponseBytes), resp.Body}
 	}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("bad http status from %s: %v", url, resp.Status)
+	validators := Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
 	}
 
-	return resp.Body, nil
+	return body, validators, nil
+}
+
+// limitedReadCloser pairs an io.LimitReader with the underlying body's
+// Close so MaxResponseBytes can cap reads without losing the ability to
+// release the connection.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
 }
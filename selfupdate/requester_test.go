@@ -0,0 +1,58 @@
+package selfupdate
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPRequesterFetchConditionalNotModified(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("manifest"))
+	}))
+	defer srv.Close()
+
+	h := &HTTPRequester{}
+
+	body, validators, err := h.FetchConditional(srv.URL, Validators{})
+	if err != nil {
+		t.Fatalf("initial FetchConditional: %v", err)
+	}
+	body.Close()
+	if validators.ETag != `"v1"` {
+		t.Fatalf("ETag = %q, want %q", validators.ETag, `"v1"`)
+	}
+
+	_, _, err = h.FetchConditional(srv.URL, validators)
+	if !errors.Is(err, ErrNotModified) {
+		t.Fatalf("FetchConditional = %v, want ErrNotModified", err)
+	}
+}
+
+func TestHTTPRequesterPinMismatch(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	wrongPin := sha256.Sum256([]byte("not the server's key"))
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	h := NewHTTPRequester(HTTPRequester{
+		RootCAs:      pool,
+		PinnedSHA256: [][]byte{wrongPin[:]},
+	})
+
+	if _, err := h.Fetch(srv.URL); err == nil {
+		t.Fatal("Fetch succeeded with a mismatched pin, want an error")
+	}
+}
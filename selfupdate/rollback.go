@@ -0,0 +1,80 @@
+package selfupdate
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Rollback atomically swaps the previous binary, retained for
+// RollbackWindow after a successful update, back into place. It returns
+// an error if there is no retained .old binary, e.g. because
+// RollbackWindow has already elapsed or no update has taken place.
+func (u *Updater) Rollback() error {
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolvedPath, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolvedPath
+	}
+
+	return rollbackAt(path)
+}
+
+// rollbackAt implements Rollback against an explicit executable path, so
+// the logic can be exercised against a temp file in tests instead of the
+// actual running binary.
+func rollbackAt(path string) error {
+	oldPath := oldBinaryPath(path)
+	if _, err := os.Stat(oldPath); err != nil {
+		return fmt.Errorf("selfupdate: no retained binary to roll back to: %w", err)
+	}
+
+	return os.Rename(oldPath, path)
+}
+
+// Verify runs cmd[0] with cmd[1:] followed by a --selfupdate-healthcheck
+// argument, and waits up to timeout for it to exit 0. If it exits
+// non-zero, doesn't exit in time, or fails to start, Verify calls Rollback
+// and returns an error describing the healthcheck failure, which also
+// carries Rollback's error when the rollback fails too.
+// Run it right after a successful Update so a build that crashes on
+// startup doesn't brick the install.
+func (u *Updater) Verify(cmd []string, timeout time.Duration) error {
+	if len(cmd) == 0 {
+		return fmt.Errorf("selfupdate: Verify requires a non-empty cmd")
+	}
+
+	args := append(append([]string{}, cmd[1:]...), "--selfupdate-healthcheck")
+	c := exec.Command(cmd[0], args...)
+
+	if err := c.Start(); err != nil {
+		if rbErr := u.Rollback(); rbErr != nil {
+			return fmt.Errorf("selfupdate: healthcheck failed to start: %w (rollback: %v)", err, rbErr)
+		}
+		return fmt.Errorf("selfupdate: healthcheck failed to start, rolled back: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.Wait() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			if rbErr := u.Rollback(); rbErr != nil {
+				return fmt.Errorf("selfupdate: healthcheck failed: %w (rollback: %v)", err, rbErr)
+			}
+			return fmt.Errorf("selfupdate: healthcheck failed, rolled back: %w", err)
+		}
+		return nil
+	case <-time.After(timeout):
+		_ = c.Process.Kill()
+		if rbErr := u.Rollback(); rbErr != nil {
+			return fmt.Errorf("selfupdate: healthcheck timed out after %s (rollback: %v)", timeout, rbErr)
+		}
+		return fmt.Errorf("selfupdate: healthcheck timed out after %s, rolled back", timeout)
+	}
+}
@@ -0,0 +1,47 @@
+package selfupdate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRollbackAtRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "myapp")
+	oldPath := oldBinaryPath(path)
+
+	if err := os.WriteFile(path, []byte("new"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(oldPath, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rollbackAt(path); err != nil {
+		t.Fatalf("rollbackAt: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "old" {
+		t.Fatalf("path contents = %q, want %q", got, "old")
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Fatalf("oldPath still exists after rollback: %v", err)
+	}
+}
+
+func TestRollbackAtNoRetainedBinary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "myapp")
+	if err := os.WriteFile(path, []byte("new"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rollbackAt(path); err == nil {
+		t.Fatal("rollbackAt succeeded with no retained binary, want an error")
+	}
+}
@@ -2,17 +2,17 @@ package selfupdate
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
-
-	"github.com/klauspost/compress/zstd"
+	"sync"
+	"time"
 )
 
 const (
@@ -24,6 +24,14 @@ var (
 	ErrHashMismatch = errors.New("new file hash mismatch after patch")
 
 	defaultHTTPRequester = HTTPRequester{}
+
+	// rollbackTimersMu guards rollbackTimers.
+	rollbackTimersMu sync.Mutex
+	// rollbackTimers tracks the pending cleanup timer for each retained
+	// .old binary, keyed by its path, so a second update started before
+	// the first's RollbackWindow elapses cancels the earlier timer
+	// instead of racing it to delete the wrong retained binary.
+	rollbackTimers = map[string]*time.Timer{}
 )
 
 // Updater is the configuration and runtime data for doing an update.
@@ -39,16 +47,34 @@ var (
 //		go updater.BackgroundRun()
 //	}
 type Updater struct {
-	CurrentVersion string    // Currently running version. `dev` is a special version here and will cause the updater to never update.
-	ApiURL         string    // Base URL for API requests (JSON files).
-	CmdName        string    // Command name is appended to the ApiURL like http://apiurl/CmdName/. This represents one binary.
-	CheckTime      int       // Time in hours before next check
-	RandomizeTime  int       // Time in hours to randomize with CheckTime
-	Requester      Requester // Optional parameter to override existing HTTP request handler
+	CurrentVersion string            // Currently running version. `dev` is a special version here and will cause the updater to never update.
+	ApiURL         string            // Base URL for API requests (JSON files). Ignored when Source is set.
+	CmdName        string            // Command name is appended to the ApiURL like http://apiurl/CmdName/. This represents one binary.
+	CheckTime      int               // Time in hours before next check
+	RandomizeTime  int               // Time in hours to randomize with CheckTime
+	Requester      Requester         // Optional parameter to override existing HTTP request handler
+	PublicKey      ed25519.PublicKey // Optional Ed25519 public key. When set, fetched binaries without a valid manifest signature are rejected.
+	Source         Source            // Optional pluggable release backend; defaults to the ApiURL/CmdName JSON layout.
+	Channel        string            // Release channel to request from Source, e.g. "stable", "beta", "nightly". Ignored by the default JSON layout.
+	RollbackWindow time.Duration     // How long the previous binary is retained after a successful update so Rollback can restore it. Zero removes it immediately.
 	Info           struct {
-		Version string
+		Version   string
+		Sha256    string // hex-encoded SHA-256 of the platform binary
+		Signature string // hex-encoded Ed25519 signature of the SHA-256 sum, required when PublicKey is set
 	}
 	OnSuccessfulUpdate func() // Optional function to run after an update has successfully taken place
+
+	artifact           Artifact   // platform artifact of Info.Version, resolved by FetchInfo
+	manifestValidators Validators // cache validators from the last manifest fetch, for conditional requests
+}
+
+// source returns the configured Source, falling back to the original
+// ApiURL/CmdName JSON layout when none is set.
+func (u *Updater) source() Source {
+	if u.Source != nil {
+		return u.Source
+	}
+	return &jsonSource{u: u}
 }
 
 func (u *Updater) getExecRelativeDir(dir string) string {
@@ -154,9 +180,27 @@ func (u *Updater) Update() error {
 	}
 	defer old.Close()
 
-	bin, err := u.fetchFullBin()
+	oldBytes, err := io.ReadAll(old)
 	if err != nil {
-		log.Println("update: fetching full binary(fetchFullBin),", err)
+		return err
+	}
+
+	var bin []byte
+	if u.Source == nil {
+		bin, err = u.fetchPatchedBin(oldBytes)
+	}
+	if bin == nil {
+		if err != nil {
+			log.Println("update: fetching patch failed, falling back to full binary,", err)
+		}
+		bin, err = u.fetchFullBin()
+		if err != nil {
+			log.Println("update: fetching full binary(fetchFullBin),", err)
+			return err
+		}
+	}
+
+	if err := u.verifyBin(bin); err != nil {
 		return err
 	}
 
@@ -164,7 +208,7 @@ func (u *Updater) Update() error {
 	// it can't be renamed if a handle to the file is still open
 	old.Close()
 
-	err, errRecover := fromStream(bytes.NewBuffer(bin))
+	err, errRecover := fromStream(bytes.NewBuffer(bin), u.RollbackWindow)
 	if errRecover != nil {
 		return fmt.Errorf("update and recovery errors: %q %q", err, errRecover)
 	}
@@ -203,11 +247,15 @@ func (u *Updater) UpdateForce() error {
 		return err
 	}
 
+	if err := u.verifyBin(bin); err != nil {
+		return err
+	}
+
 	// close the old binary before installing because on windows
 	// it can't be renamed if a handle to the file is still open
 	old.Close()
 
-	err, errRecover := fromStream(bytes.NewBuffer(bin))
+	err, errRecover := fromStream(bytes.NewBuffer(bin), u.RollbackWindow)
 	if errRecover != nil {
 		return fmt.Errorf("update and recovery errors: %q %q", err, errRecover)
 	}
@@ -223,7 +271,11 @@ func (u *Updater) UpdateForce() error {
 	return nil
 }
 
-func fromStream(updateWith io.Reader) (err error, errRecover error) {
+// fromStream swaps the running executable for the bytes read from
+// updateWith. The previous binary is kept on disk at .<name>.old for
+// rollbackWindow, so Updater.Rollback can restore it; a zero
+// rollbackWindow removes it immediately, as before.
+func fromStream(updateWith io.Reader, rollbackWindow time.Duration) (err error, errRecover error) {
 	updatePath, err := os.Executable()
 	if err != nil {
 		return
@@ -253,7 +305,7 @@ func fromStream(updateWith io.Reader) (err error, errRecover error) {
 	fp.Close()
 
 	// this is where we'll move the executable to so that we can swap in the updated replacement
-	oldPath := filepath.Join(updateDir, fmt.Sprintf(".%s.old", filename))
+	oldPath := oldBinaryPath(updatePath)
 
 	// delete any existing old exec file - this is necessary on Windows for two reasons:
 	// 1. after a successful update, Windows can't remove the .old file because the process is still running
@@ -272,6 +324,27 @@ func fromStream(updateWith io.Reader) (err error, errRecover error) {
 	if err != nil {
 		// copy unsuccessful
 		errRecover = os.Rename(oldPath, updatePath)
+	} else if rollbackWindow > 0 {
+		// keep the old binary around so Rollback can restore it if the
+		// new one turns out to be broken. oldPath is always the same
+		// path for a given executable, so cancel any timer from an
+		// earlier update before scheduling this one, or the earlier
+		// timer could fire first and delete the binary this update just
+		// retained.
+		rollbackTimersMu.Lock()
+		if prior, ok := rollbackTimers[oldPath]; ok {
+			prior.Stop()
+		}
+		rollbackTimers[oldPath] = time.AfterFunc(rollbackWindow, func() {
+			rollbackTimersMu.Lock()
+			delete(rollbackTimers, oldPath)
+			rollbackTimersMu.Unlock()
+
+			if removeErr := os.Remove(oldPath); removeErr != nil {
+				_ = hideFile(oldPath)
+			}
+		})
+		rollbackTimersMu.Unlock()
 	} else {
 		// copy successful, remove the old binary
 		errRemove := os.Remove(oldPath)
@@ -285,45 +358,76 @@ func fromStream(updateWith io.Reader) (err error, errRecover error) {
 	return
 }
 
-// FetchInfo fetches the update JSON manifest at u.ApiURL/appname/platform.json
-// and updates u.Info.
+// oldBinaryPath returns where fromStream retains the previous executable
+// after swapping updatePath for the new one.
+func oldBinaryPath(updatePath string) string {
+	updateDir := filepath.Dir(updatePath)
+	filename := filepath.Base(updatePath)
+	return filepath.Join(updateDir, fmt.Sprintf(".%s.old", filename))
+}
+
+// FetchInfo resolves the latest release on u.Channel via u.source() and
+// updates u.Info. It returns an error if the release has no artifact for
+// the running platform. If the Source reports ErrNotModified (the
+// manifest hasn't changed since the last check), u.Info is left as-is and
+// FetchInfo returns nil.
 func (u *Updater) FetchInfo() error {
-	r, err := u.fetch(u.ApiURL + url.QueryEscape(u.CmdName) + "/" + url.QueryEscape(plat) + ".json")
+	release, err := u.source().LatestVersion(context.Background(), u.Channel)
 	if err != nil {
+		if errors.Is(err, ErrNotModified) {
+			return nil
+		}
 		return err
 	}
-	defer r.Close()
-	err = json.NewDecoder(r).Decode(&u.Info)
-	if err != nil {
-		return err
+
+	artifact, ok := release.Artifacts[plat]
+	if !ok {
+		return fmt.Errorf("selfupdate: release %s has no %s artifact", release.Version, plat)
 	}
+
+	u.Info.Version = release.Version
+	u.Info.Sha256 = release.Sha256
+	u.Info.Signature = release.Signature
+	u.artifact = artifact
+
 	return nil
 }
 
 func (u *Updater) fetchFullBin() ([]byte, error) {
-	bin, err := u.fetchBin()
+	r, err := u.source().Fetch(context.Background(), u.artifact)
 	if err != nil {
 		return nil, err
 	}
-	return bin, nil
+	defer r.Close()
+
+	return io.ReadAll(r)
 }
 
-func (u *Updater) fetchBin() ([]byte, error) {
-	r, err := u.fetch(u.ApiURL + url.QueryEscape(u.CmdName) + "/" + url.QueryEscape(u.Info.Version) + "/" + url.QueryEscape(plat) + ".zst")
-	if err != nil {
-		return nil, err
+// requester returns the configured Requester, falling back to the
+// package default.
+func (u *Updater) requester() Requester {
+	if u.Requester != nil {
+		return u.Requester
 	}
-	defer r.Close()
-	buf := new(bytes.Buffer)
-	gz, err := zstd.NewReader(r)
-	if err != nil {
-		return nil, err
+	return &defaultHTTPRequester
+}
+
+// fetchManifest fetches url, making the request conditional against
+// u.manifestValidators when the configured Requester supports it, and
+// returns ErrNotModified if the server confirms nothing has changed.
+func (u *Updater) fetchManifest(url string) (io.ReadCloser, error) {
+	cr, ok := u.requester().(ConditionalRequester)
+	if !ok {
+		return u.fetch(url)
 	}
-	if _, err = io.Copy(buf, gz); err != nil {
+
+	body, validators, err := cr.FetchConditional(url, u.manifestValidators)
+	if err != nil {
 		return nil, err
 	}
+	u.manifestValidators = validators
 
-	return buf.Bytes(), nil
+	return body, nil
 }
 
 func (u *Updater) fetch(url string) (io.ReadCloser, error) {
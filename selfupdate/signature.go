@@ -0,0 +1,42 @@
+package selfupdate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrSignatureMismatch is returned when a downloaded binary's Ed25519
+// signature does not validate against the configured PublicKey.
+var ErrSignatureMismatch = errors.New("new file signature mismatch")
+
+// verifyBin checks bin against the manifest-advertised SHA-256 hash and,
+// when u.PublicKey is configured, verifies the accompanying signature.
+// Once a PublicKey has been set, a manifest with no signature is rejected.
+func (u *Updater) verifyBin(bin []byte) error {
+	sum := sha256.Sum256(bin)
+
+	if u.Info.Sha256 != "" && hex.EncodeToString(sum[:]) != u.Info.Sha256 {
+		return ErrHashMismatch
+	}
+
+	if len(u.PublicKey) == 0 {
+		return nil
+	}
+
+	if u.Info.Signature == "" {
+		return ErrSignatureMismatch
+	}
+
+	sig, err := hex.DecodeString(u.Info.Signature)
+	if err != nil {
+		return ErrSignatureMismatch
+	}
+
+	if !ed25519.Verify(u.PublicKey, sum[:], sig) {
+		return ErrSignatureMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package selfupdate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestVerifyBinHashMismatch(t *testing.T) {
+	u := &Updater{}
+	u.Info.Sha256 = hex.EncodeToString(sha256.New().Sum(nil))
+
+	if err := u.verifyBin([]byte("payload")); err != ErrHashMismatch {
+		t.Fatalf("verifyBin = %v, want ErrHashMismatch", err)
+	}
+}
+
+func TestVerifyBinNoPublicKeySkipsSignature(t *testing.T) {
+	bin := []byte("payload")
+	sum := sha256.Sum256(bin)
+
+	u := &Updater{}
+	u.Info.Sha256 = hex.EncodeToString(sum[:])
+
+	if err := u.verifyBin(bin); err != nil {
+		t.Fatalf("verifyBin = %v, want nil", err)
+	}
+}
+
+func TestVerifyBinMissingSignature(t *testing.T) {
+	bin := []byte("payload")
+	sum := sha256.Sum256(bin)
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Updater{PublicKey: pub}
+	u.Info.Sha256 = hex.EncodeToString(sum[:])
+
+	if err := u.verifyBin(bin); err != ErrSignatureMismatch {
+		t.Fatalf("verifyBin = %v, want ErrSignatureMismatch", err)
+	}
+}
+
+func TestVerifyBinBadSignature(t *testing.T) {
+	bin := []byte("payload")
+	sum := sha256.Sum256(bin)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSum := sha256.Sum256([]byte("other payload"))
+	sig := ed25519.Sign(priv, otherSum[:])
+
+	u := &Updater{PublicKey: pub}
+	u.Info.Sha256 = hex.EncodeToString(sum[:])
+	u.Info.Signature = hex.EncodeToString(sig)
+
+	if err := u.verifyBin(bin); err != ErrSignatureMismatch {
+		t.Fatalf("verifyBin = %v, want ErrSignatureMismatch", err)
+	}
+}
+
+func TestVerifyBinValidSignature(t *testing.T) {
+	bin := []byte("payload")
+	sum := sha256.Sum256(bin)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := ed25519.Sign(priv, sum[:])
+
+	u := &Updater{PublicKey: pub}
+	u.Info.Sha256 = hex.EncodeToString(sum[:])
+	u.Info.Signature = hex.EncodeToString(sig)
+
+	if err := u.verifyBin(bin); err != nil {
+		t.Fatalf("verifyBin = %v, want nil", err)
+	}
+}
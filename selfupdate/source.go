@@ -0,0 +1,100 @@
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Release describes a single version resolved by a Source.
+type Release struct {
+	Version string
+	// Sha256 and Signature mirror Updater.Info and are optional: when a
+	// Source supplies them, PublicKey verification still applies.
+	Sha256    string
+	Signature string
+	// Artifacts holds the downloadable assets of this release, keyed by
+	// platform ("linux-amd64", ...).
+	Artifacts map[string]Artifact
+}
+
+// Artifact identifies a single downloadable asset of a Release. Its shape
+// is Source-specific; only the Source that produced it needs to
+// understand it.
+type Artifact struct {
+	URL string
+}
+
+// Source resolves manifest and artifact data for an update channel,
+// decoupling Updater from any one release layout. The zero-value Updater
+// uses jsonSource, which reproduces the original ApiURL/CmdName JSON
+// layout; GitHubReleasesSource and S3Source adapt existing release
+// infrastructure to the same interface.
+type Source interface {
+	// LatestVersion returns the newest Release published on channel. An
+	// empty channel means whatever the Source considers its default.
+	LatestVersion(ctx context.Context, channel string) (Release, error)
+	// Fetch opens artifact for reading the raw, uncompressed binary. The
+	// caller is responsible for closing it.
+	Fetch(ctx context.Context, artifact Artifact) (io.ReadCloser, error)
+}
+
+// jsonSource is the default Source: a JSON manifest at
+// ApiURL/CmdName/platform.json and a zstd-compressed binary at
+// ApiURL/CmdName/version/platform.zst, both fetched through the
+// Updater's Requester.
+type jsonSource struct {
+	u *Updater
+}
+
+func (s *jsonSource) LatestVersion(ctx context.Context, channel string) (Release, error) {
+	r, err := s.u.fetchManifest(s.u.ApiURL + url.QueryEscape(s.u.CmdName) + "/" + url.QueryEscape(plat) + ".json")
+	if err != nil {
+		return Release{}, err
+	}
+	defer r.Close()
+
+	var info struct {
+		Version   string
+		Sha256    string
+		Signature string
+	}
+	if err := json.NewDecoder(r).Decode(&info); err != nil {
+		return Release{}, err
+	}
+
+	return Release{
+		Version:   info.Version,
+		Sha256:    info.Sha256,
+		Signature: info.Signature,
+		Artifacts: map[string]Artifact{
+			plat: {URL: s.u.ApiURL + url.QueryEscape(s.u.CmdName) + "/" + url.QueryEscape(info.Version) + "/" + url.QueryEscape(plat) + ".zst"},
+		},
+	}, nil
+}
+
+func (s *jsonSource) Fetch(ctx context.Context, artifact Artifact) (io.ReadCloser, error) {
+	r, err := s.u.fetch(artifact.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, zr); err != nil {
+		zr.Close()
+		return nil, err
+	}
+	zr.Close()
+
+	return io.NopCloser(buf), nil
+}
@@ -0,0 +1,245 @@
+package selfupdate
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GitHubReleasesSource resolves releases against the GitHub Releases API,
+// picking assets by matching their (extension-stripped) name against the
+// running platform, the way AdGuardHome and Clash.Meta's updaters do.
+//
+// Release.Sha256 is left empty unless ChecksumsAsset is configured, and
+// Release.Signature unless SignatureExt is: GitHub Releases carries no
+// built-in notion of either, so without SignatureExt Updater.PublicKey
+// verification deterministically fails every update with
+// ErrSignatureMismatch rather than silently accepting unsigned binaries.
+type GitHubReleasesSource struct {
+	// Owner and Repo identify the GitHub repository, e.g. "wuc656" and
+	// "go-selfupdate".
+	Owner, Repo string
+	// AssetExt is the file extension release assets carry after the
+	// platform name, e.g. ".tar.gz" or ".zip". Defaults to none.
+	AssetExt string
+	// ChecksumsAsset, when set, names a release asset holding
+	// "<sha256>  <asset name>" lines (the sha256sum/goreleaser
+	// convention), used to populate Release.Sha256 for the running
+	// platform's asset.
+	ChecksumsAsset string
+	// SignatureExt, when set, is appended to the running platform's
+	// asset name to find a companion asset (e.g.
+	// "linux-amd64.tar.gz.sig") holding the hex-encoded Ed25519
+	// signature of that asset's SHA-256 sum, matching the format
+	// go-selfupdate's own generator writes. Required for
+	// Updater.PublicKey verification to succeed against this source.
+	SignatureExt string
+	// Token, if set, is sent as a bearer token, for authenticated
+	// requests against private repositories or a higher rate limit.
+	Token string
+	// Client is the HTTP client used for both the API and asset
+	// downloads. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (s *GitHubReleasesSource) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+func (s *GitHubReleasesSource) do(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("github releases: unexpected status %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// LatestVersion fetches /releases/latest, or the newest release whose tag
+// contains channel as a substring when channel is non-empty, e.g. a
+// channel of "beta" matching a tag of "v1.2.0-beta.1". GitHub has no
+// first-class concept of channels, so this is a convention, not an API
+// feature.
+func (s *GitHubReleasesSource) LatestVersion(ctx context.Context, channel string) (Release, error) {
+	if channel == "" {
+		resp, err := s.do(ctx, fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", s.Owner, s.Repo))
+		if err != nil {
+			return Release{}, err
+		}
+		defer resp.Body.Close()
+
+		var rel githubRelease
+		if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+			return Release{}, err
+		}
+		return s.toRelease(ctx, rel)
+	}
+
+	resp, err := s.do(ctx, fmt.Sprintf("https://api.github.com/repos/%s/%s/releases?per_page=20", s.Owner, s.Repo))
+	if err != nil {
+		return Release{}, err
+	}
+	defer resp.Body.Close()
+
+	var rels []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&rels); err != nil {
+		return Release{}, err
+	}
+	for _, rel := range rels {
+		if strings.Contains(rel.TagName, channel) {
+			return s.toRelease(ctx, rel)
+		}
+	}
+
+	return Release{}, fmt.Errorf("github releases: no release matching channel %q", channel)
+}
+
+func (s *GitHubReleasesSource) toRelease(ctx context.Context, rel githubRelease) (Release, error) {
+	release := Release{
+		Version:   strings.TrimPrefix(rel.TagName, "v"),
+		Artifacts: map[string]Artifact{},
+	}
+
+	assetURLs := make(map[string]string, len(rel.Assets))
+	for _, asset := range rel.Assets {
+		assetURLs[asset.Name] = asset.BrowserDownloadURL
+		name := strings.TrimSuffix(asset.Name, s.AssetExt)
+		release.Artifacts[name] = Artifact{URL: asset.BrowserDownloadURL}
+	}
+
+	platAsset := plat + s.AssetExt
+
+	if s.ChecksumsAsset != "" {
+		checksumsURL, ok := assetURLs[s.ChecksumsAsset]
+		if !ok {
+			return Release{}, fmt.Errorf("github releases: release %s has no %q checksums asset", release.Version, s.ChecksumsAsset)
+		}
+
+		sums, err := s.fetchChecksums(ctx, checksumsURL)
+		if err != nil {
+			return Release{}, err
+		}
+
+		sum, ok := sums[platAsset]
+		if !ok {
+			return Release{}, fmt.Errorf("github releases: %q has no checksum for %s", s.ChecksumsAsset, platAsset)
+		}
+		release.Sha256 = sum
+	}
+
+	if s.SignatureExt != "" {
+		sigURL, ok := assetURLs[platAsset+s.SignatureExt]
+		if !ok {
+			return Release{}, fmt.Errorf("github releases: release %s has no %s signature asset", release.Version, platAsset+s.SignatureExt)
+		}
+
+		sig, err := s.fetchSignature(ctx, sigURL)
+		if err != nil {
+			return Release{}, err
+		}
+		release.Signature = sig
+	}
+
+	return release, nil
+}
+
+// fetchChecksums downloads and parses a sha256sum/goreleaser-style
+// checksums file into a map of asset name to hex-encoded SHA-256 sum.
+func (s *GitHubReleasesSource) fetchChecksums(ctx context.Context, url string) (map[string]string, error) {
+	resp, err := s.do(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make(map[string]string)
+	for _, line := range strings.Split(string(body), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		sums[fields[len(fields)-1]] = fields[0]
+	}
+
+	return sums, nil
+}
+
+// fetchSignature downloads a companion asset holding a hex-encoded
+// Ed25519 signature.
+func (s *GitHubReleasesSource) fetchSignature(ctx context.Context, url string) (string, error) {
+	resp, err := s.do(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	sig := strings.TrimSpace(string(body))
+	if _, err := hex.DecodeString(sig); err != nil {
+		return "", fmt.Errorf("github releases: signature asset %s is not hex-encoded: %w", url, err)
+	}
+
+	return sig, nil
+}
+
+// Fetch downloads artifact.URL, which is already a direct GitHub asset
+// URL, and returns the asset's bytes exactly as uploaded. No decompression
+// or unpacking is applied: when AssetExt names an archive such as
+// ".tar.gz" or ".zip", the bytes returned are the archive, not the
+// executable inside it.
+func (s *GitHubReleasesSource) Fetch(ctx context.Context, artifact Artifact) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, artifact.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("github releases: unexpected status %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
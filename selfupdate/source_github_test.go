@@ -0,0 +1,91 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubReleasesSourceToReleaseChecksumAndSignature(t *testing.T) {
+	assetBody := []byte("binary contents")
+	sum := sha256.Sum256(assetBody)
+	sumHex := hex.EncodeToString(sum[:])
+
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := ed25519.Sign(priv, sum[:])
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s  %s\n", sumHex, plat+".tar.gz")
+	})
+	mux.HandleFunc("/asset.sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(hex.EncodeToString(sig)))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	s := &GitHubReleasesSource{
+		AssetExt:       ".tar.gz",
+		ChecksumsAsset: "checksums.txt",
+		SignatureExt:   ".sig",
+	}
+
+	rel := githubRelease{
+		TagName: "v1.2.3",
+		Assets: []struct {
+			Name               string `json:"name"`
+			BrowserDownloadURL string `json:"browser_download_url"`
+		}{
+			{Name: plat + ".tar.gz", BrowserDownloadURL: srv.URL + "/asset.tar.gz"},
+			{Name: "checksums.txt", BrowserDownloadURL: srv.URL + "/checksums.txt"},
+			{Name: plat + ".tar.gz.sig", BrowserDownloadURL: srv.URL + "/asset.sig"},
+		},
+	}
+
+	release, err := s.toRelease(context.Background(), rel)
+	if err != nil {
+		t.Fatalf("toRelease: %v", err)
+	}
+
+	if release.Version != "1.2.3" {
+		t.Errorf("Version = %q, want %q", release.Version, "1.2.3")
+	}
+	if release.Sha256 != sumHex {
+		t.Errorf("Sha256 = %q, want %q", release.Sha256, sumHex)
+	}
+	if release.Signature != hex.EncodeToString(sig) {
+		t.Errorf("Signature = %q, want %q", release.Signature, hex.EncodeToString(sig))
+	}
+	if got := release.Artifacts[plat].URL; got != srv.URL+"/asset.tar.gz" {
+		t.Errorf("Artifacts[%s].URL = %q, want %q", plat, got, srv.URL+"/asset.tar.gz")
+	}
+}
+
+func TestGitHubReleasesSourceToReleaseMissingChecksumsAsset(t *testing.T) {
+	s := &GitHubReleasesSource{
+		AssetExt:       ".tar.gz",
+		ChecksumsAsset: "checksums.txt",
+	}
+
+	rel := githubRelease{
+		TagName: "v1.0.0",
+		Assets: []struct {
+			Name               string `json:"name"`
+			BrowserDownloadURL string `json:"browser_download_url"`
+		}{
+			{Name: plat + ".tar.gz", BrowserDownloadURL: "https://example.invalid/asset.tar.gz"},
+		},
+	}
+
+	if _, err := s.toRelease(context.Background(), rel); err == nil {
+		t.Fatal("toRelease succeeded with no checksums asset present, want an error")
+	}
+}
@@ -0,0 +1,121 @@
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// S3Source resolves releases from a bucket laid out like the default JSON
+// source (CmdName/platform.json manifests, CmdName/version/platform.zst
+// binaries), but reached via presigned URLs so no AWS credentials need to
+// be embedded in the running binary.
+type S3Source struct {
+	// CmdName matches Updater.CmdName and selects the object prefix.
+	CmdName string
+	// PresignManifest returns a presigned GET URL for the
+	// CmdName/platform.json manifest object.
+	PresignManifest func(cmdName, platform string) (string, error)
+	// PresignArtifact returns a presigned GET URL for the
+	// CmdName/version/platform.zst artifact object.
+	PresignArtifact func(cmdName, version, platform string) (string, error)
+	// Client is the HTTP client used to fetch presigned URLs. Defaults
+	// to http.DefaultClient.
+	Client *http.Client
+}
+
+func (s *S3Source) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+func (s *S3Source) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 source: unexpected status %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// LatestVersion fetches the manifest for the running platform, using
+// channel as the object prefix (e.g. "beta/myapp/linux-amd64.json") when
+// set.
+func (s *S3Source) LatestVersion(ctx context.Context, channel string) (Release, error) {
+	cmdName := s.CmdName
+	if channel != "" {
+		cmdName = channel + "/" + cmdName
+	}
+
+	manifestURL, err := s.PresignManifest(cmdName, plat)
+	if err != nil {
+		return Release{}, err
+	}
+
+	r, err := s.get(ctx, manifestURL)
+	if err != nil {
+		return Release{}, err
+	}
+	defer r.Close()
+
+	var info struct {
+		Version   string
+		Sha256    string
+		Signature string
+	}
+	if err := json.NewDecoder(r).Decode(&info); err != nil {
+		return Release{}, err
+	}
+
+	artifactURL, err := s.PresignArtifact(cmdName, info.Version, plat)
+	if err != nil {
+		return Release{}, err
+	}
+
+	return Release{
+		Version:   info.Version,
+		Sha256:    info.Sha256,
+		Signature: info.Signature,
+		Artifacts: map[string]Artifact{
+			plat: {URL: artifactURL},
+		},
+	}, nil
+}
+
+// Fetch downloads and decompresses the zstd artifact at artifact.URL.
+func (s *S3Source) Fetch(ctx context.Context, artifact Artifact) (io.ReadCloser, error) {
+	r, err := s.get(ctx, artifact.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, zr); err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(buf), nil
+}
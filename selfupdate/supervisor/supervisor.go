@@ -0,0 +1,171 @@
+// Package supervisor implements a zero-downtime restart model for programs
+// managed by selfupdate. The caller turns main() into a Prog(state State)
+// callback; a long-lived parent process supervises a child that runs Prog,
+// and hands off to a freshly updated binary by starting a new child and
+// only terminating the old one once the new one signals it is ready.
+//
+// main() calls Listen for every address it needs, then Run. Run re-execs
+// the same binary to create the child, so both calls execute again inside
+// it; Listen detects this (see IsChild) and, instead of binding the
+// address again, returns the listener the previous process handed off.
+package supervisor
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	// envRole distinguishes the top-level supervisor invocation from the
+	// child invocation it forks to actually run Prog.
+	envRole   = "GO_SELFUPDATE_ROLE"
+	roleChild = "child"
+
+	// envUpgraded, when set in the child's environment, marks it as a
+	// post-upgrade restart rather than the initial run.
+	envUpgraded = "GO_SELFUPDATE_UPGRADED"
+
+	// envNumListeners tells a Unix child how many inherited listener fds
+	// (starting at fd 3) it should pick up before the readiness pipe.
+	envNumListeners = "GO_SELFUPDATE_NUM_LISTENERS"
+
+	// envReadyPipe carries the name of the Windows named pipe a child
+	// dials to report readiness, since Windows can't inherit arbitrary
+	// fds across exec the way Unix can.
+	envReadyPipe = "GO_SELFUPDATE_READY_PIPE"
+)
+
+// readyByte is written by the child to report that it is ready to take
+// over: on Unix as soon as it has picked up its inherited listeners, on
+// Windows just before Prog is invoked. Either way it is sent before Prog
+// runs.
+const readyByte = 1
+
+// defaultRestartTimeout is used when Supervisor.RestartTimeout is unset.
+const defaultRestartTimeout = 30 * time.Second
+
+// State tells Prog whether this is the initial run of the program or a
+// restart after a self-update swapped the on-disk binary, and carries any
+// listeners inherited from the previous process.
+type State struct {
+	// Upgraded is true when this process replaces one that was already
+	// running, i.e. it was started by Supervisor.Restart.
+	Upgraded bool
+	// Listeners holds the listeners inherited from the previous process,
+	// in the same order Listen was called to bind them. Prog can use
+	// this instead of capturing Listen's return values directly.
+	Listeners []net.Listener
+}
+
+// Prog is supplied by the caller in place of main(). It should block for
+// the lifetime of the program and return when it is done. Readiness is
+// reported to the supervisor by this package before Prog is invoked (see
+// readyByte); Prog has no ready function of its own to call.
+type Prog func(state State) error
+
+// Supervisor runs Prog in a supervised child process and restarts it in
+// place when Restart is called after a self-update has replaced the
+// on-disk binary.
+type Supervisor struct {
+	// Prog is invoked inside the child process.
+	Prog Prog
+	// RestartTimeout bounds how long Restart waits for the newly started
+	// child to report readiness before giving up and keeping the old
+	// child running. Defaults to 30s.
+	RestartTimeout time.Duration
+	// PreUpgrade, if set, runs in the parent before the old child is
+	// asked to hand off to a freshly started one.
+	PreUpgrade func() error
+	// PostUpgrade, if set, runs in the parent once the new child has
+	// signalled readiness and the old child has been told to stop
+	// (SIGTERM on Unix, killed on Windows). Restart does not wait for the
+	// old child to exit before calling it.
+	PostUpgrade func()
+
+	listeners []net.Listener
+
+	mu    sync.Mutex
+	child *exec.Cmd
+
+	// childOnce guards loading the listeners handed off by the previous
+	// process; childConn, when non-nil, is the still-open connection
+	// loadChildListeners used to receive them and on which runChild
+	// later reports readiness (Windows only - Unix reports readiness on
+	// a plain inherited fd instead).
+	childOnce      sync.Once
+	childListeners []net.Listener
+	childErr       error
+	childIdx       int
+	childConn      net.Conn
+}
+
+// restartTimeout returns RestartTimeout, falling back to
+// defaultRestartTimeout when unset.
+func (s *Supervisor) restartTimeout() time.Duration {
+	if s.RestartTimeout > 0 {
+		return s.RestartTimeout
+	}
+	return defaultRestartTimeout
+}
+
+// New creates a Supervisor that runs prog with the default
+// RestartTimeout. It binds no addresses; listeners to be handed off across
+// a restart are bound with Listen.
+func New(prog Prog) *Supervisor {
+	return &Supervisor{
+		Prog:           prog,
+		RestartTimeout: defaultRestartTimeout,
+	}
+}
+
+// IsChild reports whether the calling process is the child Run re-execs
+// to run Prog, as opposed to the top-level supervisor invocation. Listen
+// already uses this to decide whether to bind or inherit; call it
+// directly if main() has other setup that only the supervisor should do.
+func IsChild() bool {
+	return os.Getenv(envRole) == roleChild
+}
+
+// Listen binds addr with network (e.g. "tcp") and registers the resulting
+// listener to be inherited across restarts. It must be called before Run.
+//
+// In the child (see IsChild), Listen does not bind addr at all - addr is
+// already in use by the still-running previous process. It instead
+// returns the listener the previous process handed off, matched to this
+// call by position: the Nth Listen call in the child returns whatever the
+// Nth Listen call in the supervisor bound.
+func (s *Supervisor) Listen(network, addr string) (net.Listener, error) {
+	if IsChild() {
+		return s.nextChildListener()
+	}
+
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	s.listeners = append(s.listeners, l)
+	return l, nil
+}
+
+// nextChildListener returns the next listener handed off by the previous
+// process, loading the full set (implemented per-OS, see
+// supervisor_unix.go / supervisor_windows.go) on first use.
+func (s *Supervisor) nextChildListener() (net.Listener, error) {
+	s.childOnce.Do(func() {
+		s.childListeners, s.childConn, s.childErr = s.loadChildListeners()
+	})
+	if s.childErr != nil {
+		return nil, s.childErr
+	}
+	if s.childIdx >= len(s.childListeners) {
+		return nil, fmt.Errorf("supervisor: Listen called more times in the child than listeners were handed off")
+	}
+
+	l := s.childListeners[s.childIdx]
+	s.childIdx++
+	return l, nil
+}
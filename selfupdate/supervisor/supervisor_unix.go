@@ -0,0 +1,238 @@
+//go:build !windows
+
+package supervisor
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Run starts the program under supervision. The first invocation becomes
+// the supervisor and forks a child to run Prog; the forked invocation
+// (identified via envRole) runs Prog in-process, inheriting any listeners
+// the supervisor bound with Listen, and reports readiness back over a
+// pipe inherited alongside them.
+func (s *Supervisor) Run() error {
+	if os.Getenv(envRole) == roleChild {
+		return s.runChild()
+	}
+	return s.runSupervisor()
+}
+
+// loadChildListeners inherits the listener fds the parent passed via
+// ExtraFiles (fds 3..3+n-1) and reports readiness on the fd right after
+// them. It backs Supervisor.Listen in the child and is also what runChild
+// falls back to triggering when Listen is never called, so readiness is
+// always reported.
+func (s *Supervisor) loadChildListeners() ([]net.Listener, net.Conn, error) {
+	n, _ := strconv.Atoi(os.Getenv(envNumListeners))
+
+	listeners := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		f := os.NewFile(uintptr(3+i), fmt.Sprintf("listener-%d", i))
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("supervisor: inheriting listener %d: %w", i, err)
+		}
+		listeners = append(listeners, l)
+	}
+
+	if ready := os.NewFile(uintptr(3+n), "ready"); ready != nil {
+		ready.Write([]byte{readyByte})
+		ready.Close()
+	}
+
+	return listeners, nil, nil
+}
+
+func (s *Supervisor) runChild() error {
+	s.childOnce.Do(func() {
+		s.childListeners, s.childConn, s.childErr = s.loadChildListeners()
+	})
+	if s.childErr != nil {
+		return s.childErr
+	}
+
+	return s.Prog(State{
+		Upgraded:  os.Getenv(envUpgraded) == "1",
+		Listeners: s.childListeners,
+	})
+}
+
+func (s *Supervisor) runSupervisor() error {
+	child, err := s.startChild(false)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.child = child
+	s.mu.Unlock()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT, syscall.SIGUSR2)
+	go func() {
+		for sg := range sig {
+			if sg == syscall.SIGUSR2 {
+				if err := s.Restart(); err != nil {
+					fmt.Fprintln(os.Stderr, "supervisor: restart failed:", err)
+				}
+				continue
+			}
+			s.mu.Lock()
+			c := s.child
+			s.mu.Unlock()
+			if c != nil {
+				c.Process.Signal(sg)
+			}
+		}
+	}()
+
+	for {
+		s.mu.Lock()
+		c := s.child
+		s.mu.Unlock()
+
+		err := c.Wait()
+
+		s.mu.Lock()
+		current := s.child == c
+		s.mu.Unlock()
+		if current {
+			return err
+		}
+		// c was the outgoing child from a Restart; keep supervising the new one.
+	}
+}
+
+// Restart starts a fresh child running the on-disk binary and, once it
+// reports readiness, terminates the previous child. Call this from
+// Updater.OnSuccessfulUpdate after selfupdate has swapped the executable
+// on disk.
+func (s *Supervisor) Restart() error {
+	if s.PreUpgrade != nil {
+		if err := s.PreUpgrade(); err != nil {
+			return err
+		}
+	}
+
+	newChild, err := s.startChild(true)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	old := s.child
+	s.child = newChild
+	s.mu.Unlock()
+
+	if old != nil {
+		old.Process.Signal(syscall.SIGTERM)
+	}
+
+	if s.PostUpgrade != nil {
+		s.PostUpgrade()
+	}
+
+	return nil
+}
+
+// startChild forks a child running the current on-disk binary, handing
+// off the supervisor's listeners and a readiness pipe via ExtraFiles, and
+// blocks until the child reports readiness or RestartTimeout elapses.
+func (s *Supervisor) startChild(upgraded bool) (*exec.Cmd, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer readyR.Close()
+
+	extraFiles := make([]*os.File, 0, len(s.listeners)+1)
+	for _, l := range s.listeners {
+		f, err := listenerFile(l)
+		if err != nil {
+			readyW.Close()
+			return nil, err
+		}
+		extraFiles = append(extraFiles, f)
+	}
+	extraFiles = append(extraFiles, readyW)
+
+	env := append(os.Environ(),
+		envRole+"="+roleChild,
+		envNumListeners+"="+strconv.Itoa(len(s.listeners)))
+	if upgraded {
+		env = append(env, envUpgraded+"=1")
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = extraFiles
+
+	if startErr := cmd.Start(); startErr != nil {
+		readyW.Close()
+		return nil, startErr
+	}
+	readyW.Close()
+	for _, f := range extraFiles[:len(extraFiles)-1] {
+		f.Close()
+	}
+
+	if err := waitReady(readyR, s.restartTimeout()); err != nil {
+		cmd.Process.Kill()
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
+// listenerFile duplicates l's underlying fd so it can be passed to a
+// child via ExtraFiles.
+func listenerFile(l net.Listener) (*os.File, error) {
+	type filer interface {
+		File() (*os.File, error)
+	}
+	f, ok := l.(filer)
+	if !ok {
+		return nil, fmt.Errorf("supervisor: listener %T cannot be handed off across a restart", l)
+	}
+	return f.File()
+}
+
+func waitReady(r *os.File, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		if _, err := r.Read(buf); err != nil {
+			done <- err
+			return
+		}
+		if buf[0] != readyByte {
+			done <- fmt.Errorf("supervisor: unexpected readiness byte")
+			return
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("supervisor: timed out waiting for child readiness")
+	}
+}
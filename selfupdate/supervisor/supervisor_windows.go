@@ -0,0 +1,277 @@
+//go:build windows
+
+package supervisor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+
+	winio "github.com/Microsoft/go-winio"
+	"golang.org/x/sys/windows"
+)
+
+// Run starts the program under supervision. Windows can't inherit
+// arbitrary file descriptors across exec the way Unix can, so listener
+// handles are duplicated into the child process explicitly and their
+// values relayed over a named pipe, alongside the readiness handshake.
+func (s *Supervisor) Run() error {
+	if os.Getenv(envRole) == roleChild {
+		return s.runChild()
+	}
+	return s.runSupervisor()
+}
+
+// loadChildListeners dials the named pipe the parent created, reads the
+// listener handles it duplicated into this process, and keeps the
+// connection open so runChild can report readiness on it once Prog is
+// about to start. It backs Supervisor.Listen in the child and is also
+// what runChild falls back to triggering when Listen is never called, so
+// readiness is always reported.
+func (s *Supervisor) loadChildListeners() ([]net.Listener, net.Conn, error) {
+	pipeName := os.Getenv(envReadyPipe)
+	if pipeName == "" {
+		return nil, nil, nil
+	}
+
+	conn, err := winio.DialPipe(pipeName, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("supervisor: dialing ready pipe: %w", err)
+	}
+
+	listeners, err := receiveListeners(conn)
+	if err != nil {
+		return nil, conn, fmt.Errorf("supervisor: receiving inherited listeners: %w", err)
+	}
+
+	return listeners, conn, nil
+}
+
+func (s *Supervisor) runChild() error {
+	s.childOnce.Do(func() {
+		s.childListeners, s.childConn, s.childErr = s.loadChildListeners()
+	})
+
+	if s.childConn != nil {
+		s.childConn.Write([]byte{readyByte})
+		s.childConn.Close()
+	}
+
+	if s.childErr != nil {
+		return s.childErr
+	}
+
+	return s.Prog(State{
+		Upgraded:  os.Getenv(envUpgraded) == "1",
+		Listeners: s.childListeners,
+	})
+}
+
+// receiveListeners reads the handle values the supervisor wrote to conn
+// and rebuilds them as net.Listeners in this process.
+func receiveListeners(conn net.Conn) ([]net.Listener, error) {
+	var n uint32
+	if err := binary.Read(conn, binary.LittleEndian, &n); err != nil {
+		return nil, err
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	for i := uint32(0); i < n; i++ {
+		var h uint64
+		if err := binary.Read(conn, binary.LittleEndian, &h); err != nil {
+			return nil, err
+		}
+
+		f := os.NewFile(uintptr(h), fmt.Sprintf("listener-%d", i))
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("rebuilding listener %d: %w", i, err)
+		}
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+func (s *Supervisor) runSupervisor() error {
+	child, err := s.startChild(false)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.child = child
+	s.mu.Unlock()
+
+	for {
+		s.mu.Lock()
+		c := s.child
+		s.mu.Unlock()
+
+		err := c.Wait()
+
+		s.mu.Lock()
+		current := s.child == c
+		s.mu.Unlock()
+		if current {
+			return err
+		}
+		// c was the outgoing child from a Restart; keep supervising the new one.
+	}
+}
+
+// Restart starts a fresh child running the on-disk binary and, once it
+// reports readiness over its named pipe, terminates the previous child.
+// Call this from Updater.OnSuccessfulUpdate after selfupdate has swapped
+// the executable on disk.
+func (s *Supervisor) Restart() error {
+	if s.PreUpgrade != nil {
+		if err := s.PreUpgrade(); err != nil {
+			return err
+		}
+	}
+
+	newChild, err := s.startChild(true)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	old := s.child
+	s.child = newChild
+	s.mu.Unlock()
+
+	if old != nil {
+		old.Process.Kill()
+	}
+
+	if s.PostUpgrade != nil {
+		s.PostUpgrade()
+	}
+
+	return nil
+}
+
+func (s *Supervisor) startChild(upgraded bool) (*exec.Cmd, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	pipeName := fmt.Sprintf(`\\.\pipe\go-selfupdate-%d`, os.Getpid())
+	ln, err := winio.ListenPipe(pipeName, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+
+	env := append(os.Environ(), envRole+"="+roleChild, envReadyPipe+"="+pipeName)
+	if upgraded {
+		env = append(env, envUpgraded+"=1")
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	if err := s.handOff(ln, cmd); err != nil {
+		_ = cmd.Process.Kill()
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
+// handOff accepts the child's connection on the readiness pipe, duplicates
+// the supervisor's listener handles into the child's process so it can
+// rebuild them instead of rebinding the address, and waits for the child
+// to report readiness.
+func (s *Supervisor) handOff(ln net.Listener, cmd *exec.Cmd) error {
+	deadline := time.Now().Add(s.restartTimeout())
+	if dl, ok := ln.(interface{ SetDeadline(time.Time) error }); ok {
+		dl.SetDeadline(deadline)
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		return fmt.Errorf("supervisor: timed out waiting for child to connect: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	childHandles, err := duplicateListenersTo(s.listeners, uint32(cmd.Process.Pid))
+	if err != nil {
+		return err
+	}
+
+	if err := binary.Write(conn, binary.LittleEndian, uint32(len(childHandles))); err != nil {
+		return err
+	}
+	for _, h := range childHandles {
+		if err := binary.Write(conn, binary.LittleEndian, uint64(h)); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err != nil || buf[0] != readyByte {
+		return fmt.Errorf("supervisor: unexpected readiness signal")
+	}
+
+	return nil
+}
+
+// duplicateListenersTo duplicates each listener's underlying handle into
+// the process identified by pid, since Windows has no fd-inheritance
+// equivalent to Unix's ExtraFiles.
+func duplicateListenersTo(listeners []net.Listener, pid uint32) ([]windows.Handle, error) {
+	childProcess, err := windows.OpenProcess(windows.PROCESS_DUP_HANDLE, false, pid)
+	if err != nil {
+		return nil, fmt.Errorf("supervisor: opening child process for handle duplication: %w", err)
+	}
+	defer windows.CloseHandle(childProcess)
+
+	currentProcess := windows.CurrentProcess()
+
+	handles := make([]windows.Handle, 0, len(listeners))
+	for _, l := range listeners {
+		f, err := listenerFile(l)
+		if err != nil {
+			return nil, err
+		}
+
+		var dup windows.Handle
+		err = windows.DuplicateHandle(currentProcess, windows.Handle(f.Fd()), childProcess, &dup, 0, true, windows.DUPLICATE_SAME_ACCESS)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("supervisor: duplicating listener handle: %w", err)
+		}
+
+		handles = append(handles, dup)
+	}
+
+	return handles, nil
+}
+
+// listenerFile returns the *os.File backing l, so its handle can be
+// duplicated into the child process.
+func listenerFile(l net.Listener) (*os.File, error) {
+	type filer interface {
+		File() (*os.File, error)
+	}
+	f, ok := l.(filer)
+	if !ok {
+		return nil, fmt.Errorf("supervisor: listener %T cannot be handed off across a restart", l)
+	}
+	return f.File()
+}